@@ -0,0 +1,25 @@
+// Package authn provides the interface for non-interactive authentication
+// connectors used by the resource owner password credentials (ROPC) flow.
+//
+// Each connector verifies a username and password against an external
+// identity store (an LDAP directory, a static password file, the OIDC
+// issuer itself, and so on) and returns the resulting Identity so that
+// usecases.Login and usecases.GetToken can synthesize an ID token locally.
+package authn
+
+import "context"
+
+// Identity represents the subject returned by a connector after a
+// successful authentication.
+type Identity struct {
+	Subject string
+	Groups  []string
+	Email   string
+	Extras  map[string]string
+}
+
+// Connector authenticates a username and password against a backend and
+// returns the resulting Identity.
+type Connector interface {
+	Authenticate(ctx context.Context, username, password string) (Identity, error)
+}
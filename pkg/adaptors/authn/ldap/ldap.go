@@ -0,0 +1,115 @@
+// Package ldap provides an authn.Connector backed by an LDAP directory.
+//
+// It binds as a service account, searches for the user entry and the
+// user's groups while still bound as that service account (many
+// directories restrict anonymous/user read access on the group tree), and
+// only then rebinds as the user to verify the password.
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+
+	"github.com/int128/kubelogin/pkg/adaptors/authn"
+	"golang.org/x/xerrors"
+	"gopkg.in/ldap.v3"
+)
+
+// Conn is the subset of *ldap.Conn that Connector depends on, so that
+// Authenticate can be unit tested against a fake.
+type Conn interface {
+	Bind(username, password string) error
+	Search(req *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// Config represents the settings required to reach an LDAP directory and
+// map its entries onto an authn.Identity.
+type Config struct {
+	Host               string
+	BindDN             string
+	BindPassword       string
+	UserSearchBaseDN   string
+	UserSearchFilter   string
+	GroupSearchBaseDN  string
+	GroupSearchFilter  string
+	GroupAttribute     string
+	InsecureSkipVerify bool
+}
+
+// Connector implements authn.Connector for an LDAP directory.
+type Connector struct {
+	Config Config
+	Dial   func(host string) (Conn, error)
+}
+
+// New returns a Connector for the given configuration, dialing the LDAP
+// server with the default TLS-aware dialer. If c.InsecureSkipVerify is
+// set, the server's certificate is not verified; this is only intended
+// for testing against a directory with a self-signed certificate.
+func New(c Config) *Connector {
+	return &Connector{
+		Config: c,
+		Dial: func(host string) (Conn, error) {
+			if c.InsecureSkipVerify {
+				return ldap.DialURL(host, ldap.DialWithTLSConfig(&tls.Config{InsecureSkipVerify: true}))
+			}
+			return ldap.DialURL(host)
+		},
+	}
+}
+
+// Authenticate performs a service-account bind, searches for the user by
+// UserSearchFilter, searches GroupSearchBaseDN for the user's groups while
+// still bound as the service account, and only then rebinds as the user to
+// verify the password.
+func (c *Connector) Authenticate(ctx context.Context, username, password string) (authn.Identity, error) {
+	conn, err := c.Dial(c.Config.Host)
+	if err != nil {
+		return authn.Identity{}, xerrors.Errorf("could not dial the LDAP server: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(c.Config.BindDN, c.Config.BindPassword); err != nil {
+		return authn.Identity{}, xerrors.Errorf("could not bind as the service account: %w", err)
+	}
+
+	userFilter := fmt.Sprintf(c.Config.UserSearchFilter, ldap.EscapeFilter(username))
+	searchResult, err := conn.Search(ldap.NewSearchRequest(
+		c.Config.UserSearchBaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		userFilter, []string{"mail"}, nil,
+	))
+	if err != nil {
+		return authn.Identity{}, xerrors.Errorf("could not search for the user: %w", err)
+	}
+	if len(searchResult.Entries) != 1 {
+		return authn.Identity{}, xerrors.Errorf("expected 1 user entry but found %d", len(searchResult.Entries))
+	}
+	userEntry := searchResult.Entries[0]
+
+	groupFilter := fmt.Sprintf(c.Config.GroupSearchFilter, ldap.EscapeFilter(userEntry.DN))
+	groupResult, err := conn.Search(ldap.NewSearchRequest(
+		c.Config.GroupSearchBaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		groupFilter, []string{c.Config.GroupAttribute}, nil,
+	))
+	if err != nil {
+		return authn.Identity{}, xerrors.Errorf("could not search for the groups: %w", err)
+	}
+	var groups []string
+	for _, entry := range groupResult.Entries {
+		groups = append(groups, entry.GetAttributeValue(c.Config.GroupAttribute))
+	}
+
+	if err := conn.Bind(userEntry.DN, password); err != nil {
+		return authn.Identity{}, xerrors.Errorf("could not bind as the user: %w", err)
+	}
+
+	return authn.Identity{
+		Subject: userEntry.DN,
+		Groups:  groups,
+		Email:   userEntry.GetAttributeValue("mail"),
+	}, nil
+}
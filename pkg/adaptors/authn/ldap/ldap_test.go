@@ -0,0 +1,195 @@
+package ldap
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"golang.org/x/xerrors"
+	"gopkg.in/ldap.v3"
+)
+
+// fakeConn is a Conn that serves a single user entry and its groups from an
+// in-memory directory, and records the sequence of DNs passed to Bind, so
+// that tests can assert the service account is never
+// replaced by the user's bind before the group search runs.
+type fakeConn struct {
+	servicePassword string
+	userPassword    string
+	userEntry       *ldap.Entry
+	groupEntries    []*ldap.Entry
+
+	binds  []string
+	closed bool
+}
+
+func (f *fakeConn) Bind(username, password string) error {
+	f.binds = append(f.binds, username)
+	switch {
+	case username == "cn=service,dc=example,dc=com":
+		if password != f.servicePassword {
+			return xerrors.New("invalid service account credentials")
+		}
+		return nil
+	case f.userEntry != nil && username == f.userEntry.DN:
+		if password != f.userPassword {
+			return xerrors.New("invalid user credentials")
+		}
+		return nil
+	default:
+		return xerrors.New("no such entry")
+	}
+}
+
+func (f *fakeConn) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	if req.BaseDN == "ou=groups,dc=example,dc=com" {
+		return &ldap.SearchResult{Entries: f.groupEntries}, nil
+	}
+	if f.userEntry == nil {
+		return &ldap.SearchResult{}, nil
+	}
+	return &ldap.SearchResult{Entries: []*ldap.Entry{f.userEntry}}, nil
+}
+
+func (f *fakeConn) Close() error {
+	f.closed = true
+	return nil
+}
+
+func newTestConnector(conn *fakeConn) *Connector {
+	return &Connector{
+		Config: Config{
+			Host:              "ldap://ldap.example.com",
+			BindDN:            "cn=service,dc=example,dc=com",
+			BindPassword:      conn.servicePassword,
+			UserSearchBaseDN:  "ou=people,dc=example,dc=com",
+			UserSearchFilter:  "(uid=%s)",
+			GroupSearchBaseDN: "ou=groups,dc=example,dc=com",
+			GroupSearchFilter: "(member=%s)",
+			GroupAttribute:    "cn",
+		},
+		Dial: func(string) (Conn, error) { return conn, nil },
+	}
+}
+
+func TestConnector_Authenticate(t *testing.T) {
+	userEntry := ldap.NewEntry("uid=alice,ou=people,dc=example,dc=com", map[string][]string{
+		"mail": {"alice@example.com"},
+	})
+	groupEntries := []*ldap.Entry{
+		ldap.NewEntry("cn=admins,ou=groups,dc=example,dc=com", map[string][]string{"cn": {"admins"}}),
+		ldap.NewEntry("cn=dev,ou=groups,dc=example,dc=com", map[string][]string{"cn": {"dev"}}),
+	}
+
+	t.Run("Match", func(t *testing.T) {
+		conn := &fakeConn{servicePassword: "service-secret", userPassword: "hunter2", userEntry: userEntry, groupEntries: groupEntries}
+		c := newTestConnector(conn)
+
+		got, err := c.Authenticate(context.TODO(), "alice", "hunter2")
+		if err != nil {
+			t.Fatalf("Authenticate() returned an error: %s", err)
+		}
+		if got.Subject != userEntry.DN {
+			t.Errorf("Subject = %s, want %s", got.Subject, userEntry.DN)
+		}
+		if got.Email != "alice@example.com" {
+			t.Errorf("Email = %s, want alice@example.com", got.Email)
+		}
+		if len(got.Groups) != 2 || got.Groups[0] != "admins" || got.Groups[1] != "dev" {
+			t.Errorf("Groups = %v, want [admins dev]", got.Groups)
+		}
+		if !conn.closed {
+			t.Errorf("expected the connection to be closed")
+		}
+		// The group search must run while still bound as the service
+		// account: the user bind (to verify the password) is the last
+		// bind, not the first.
+		if len(conn.binds) != 2 || conn.binds[len(conn.binds)-1] != userEntry.DN {
+			t.Errorf("binds = %v, want the final bind to be the user DN", conn.binds)
+		}
+	})
+
+	t.Run("WrongPassword", func(t *testing.T) {
+		conn := &fakeConn{servicePassword: "service-secret", userPassword: "hunter2", userEntry: userEntry, groupEntries: groupEntries}
+		c := newTestConnector(conn)
+
+		if _, err := c.Authenticate(context.TODO(), "alice", "wrong-password"); err == nil {
+			t.Fatalf("expected an error but got none")
+		}
+	})
+
+	t.Run("UnknownUser", func(t *testing.T) {
+		conn := &fakeConn{servicePassword: "service-secret", userPassword: "hunter2"}
+		c := newTestConnector(conn)
+
+		if _, err := c.Authenticate(context.TODO(), "carol", "hunter2"); err == nil {
+			t.Fatalf("expected an error but got none")
+		}
+	})
+
+	t.Run("ServiceAccountBindFails", func(t *testing.T) {
+		conn := &fakeConn{servicePassword: "service-secret", userPassword: "hunter2", userEntry: userEntry, groupEntries: groupEntries}
+		c := newTestConnector(conn)
+		c.Config.BindPassword = "wrong-service-secret"
+
+		if _, err := c.Authenticate(context.TODO(), "alice", "hunter2"); err == nil {
+			t.Fatalf("expected an error but got none")
+		}
+	})
+}
+
+// TestConnector_Authenticate_Integration exercises Connector against a real
+// LDAP directory, as a supplement to the fake-backed unit tests above. It
+// is skipped unless the KUBELOGIN_TEST_LDAP_HOST environment variable
+// points at a directory seeded with a matching user and group, as set up
+// by the project's integration test environment.
+func TestConnector_Authenticate_Integration(t *testing.T) {
+	host := os.Getenv("KUBELOGIN_TEST_LDAP_HOST")
+	if host == "" {
+		t.Skip("KUBELOGIN_TEST_LDAP_HOST is not set")
+	}
+
+	c := New(Config{
+		Host:              host,
+		BindDN:            os.Getenv("KUBELOGIN_TEST_LDAP_BIND_DN"),
+		BindPassword:      os.Getenv("KUBELOGIN_TEST_LDAP_BIND_PASSWORD"),
+		UserSearchBaseDN:  os.Getenv("KUBELOGIN_TEST_LDAP_USER_SEARCH_BASE_DN"),
+		UserSearchFilter:  "(uid=%s)",
+		GroupSearchBaseDN: os.Getenv("KUBELOGIN_TEST_LDAP_GROUP_SEARCH_BASE_DN"),
+		GroupSearchFilter: "(member=%s)",
+		GroupAttribute:    "cn",
+	})
+
+	got, err := c.Authenticate(context.TODO(),
+		os.Getenv("KUBELOGIN_TEST_LDAP_USERNAME"),
+		os.Getenv("KUBELOGIN_TEST_LDAP_PASSWORD"),
+	)
+	if err != nil {
+		t.Fatalf("Authenticate() returned an error: %s", err)
+	}
+	if got.Subject == "" {
+		t.Errorf("expected a non-empty Subject")
+	}
+}
+
+func TestConnector_Authenticate_WrongPassword_Integration(t *testing.T) {
+	host := os.Getenv("KUBELOGIN_TEST_LDAP_HOST")
+	if host == "" {
+		t.Skip("KUBELOGIN_TEST_LDAP_HOST is not set")
+	}
+
+	c := New(Config{
+		Host:              host,
+		BindDN:            os.Getenv("KUBELOGIN_TEST_LDAP_BIND_DN"),
+		BindPassword:      os.Getenv("KUBELOGIN_TEST_LDAP_BIND_PASSWORD"),
+		UserSearchBaseDN:  os.Getenv("KUBELOGIN_TEST_LDAP_USER_SEARCH_BASE_DN"),
+		UserSearchFilter:  "(uid=%s)",
+		GroupSearchBaseDN: os.Getenv("KUBELOGIN_TEST_LDAP_GROUP_SEARCH_BASE_DN"),
+		GroupSearchFilter: "(member=%s)",
+		GroupAttribute:    "cn",
+	})
+
+	if _, err := c.Authenticate(context.TODO(), os.Getenv("KUBELOGIN_TEST_LDAP_USERNAME"), "wrong-password"); err == nil {
+		t.Errorf("expected an error but got none")
+	}
+}
@@ -0,0 +1,86 @@
+// Package staticpasswords provides an authn.Connector backed by a local
+// file of username/password/identity entries, for environments where
+// standing up an LDAP directory is not worth it.
+package staticpasswords
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/csv"
+	"os"
+	"strings"
+
+	"github.com/int128/kubelogin/pkg/adaptors/authn"
+	"golang.org/x/xerrors"
+)
+
+// entry represents a single line of the password file: password (in
+// plaintext, matching the CSV format used by the Kubernetes apiserver's
+// --basic-auth-file / static-password authenticators), email, subject and
+// comma-separated groups.
+type entry struct {
+	email    string
+	password string
+	subject  string
+	groups   []string
+}
+
+// Connector implements authn.Connector by reading a CSV file of the form
+// `password,email,subject,"group1,group2"` on every Authenticate call, so
+// that edits to the file take effect without restarting anything.
+type Connector struct {
+	Filename string
+}
+
+// New returns a Connector that reads entries from filename.
+func New(filename string) *Connector {
+	return &Connector{Filename: filename}
+}
+
+// Authenticate reads the password file and returns the Identity of the
+// matching entry, comparing passwords in constant time.
+func (c *Connector) Authenticate(_ context.Context, username, password string) (authn.Identity, error) {
+	entries, err := c.readEntries()
+	if err != nil {
+		return authn.Identity{}, xerrors.Errorf("could not read the password file: %w", err)
+	}
+	for _, e := range entries {
+		if e.email != username {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(e.password), []byte(password)) != 1 {
+			return authn.Identity{}, xerrors.New("password did not match")
+		}
+		return authn.Identity{Subject: e.subject, Groups: e.groups, Email: e.email}, nil
+	}
+	return authn.Identity{}, xerrors.New("username was not found")
+}
+
+func (c *Connector) readEntries() ([]entry, error) {
+	f, err := os.Open(c.Filename)
+	if err != nil {
+		return nil, xerrors.Errorf("could not open %s: %w", c.Filename, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(bufio.NewReader(f))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, xerrors.Errorf("could not parse %s as CSV: %w", c.Filename, err)
+	}
+
+	var entries []entry
+	for _, record := range records {
+		if len(record) < 3 {
+			return nil, xerrors.Errorf("expected at least 3 fields but found %d", len(record))
+		}
+		e := entry{password: record[0], email: record[1], subject: record[2]}
+		if len(record) >= 4 && record[3] != "" {
+			e.groups = strings.Split(record[3], ",")
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
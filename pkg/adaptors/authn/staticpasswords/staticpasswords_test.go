@@ -0,0 +1,76 @@
+package staticpasswords
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/int128/kubelogin/pkg/adaptors/authn"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "passwords.csv")
+	if err := os.WriteFile(name, []byte(content), 0600); err != nil {
+		t.Fatalf("could not write %s: %s", name, err)
+	}
+	return name
+}
+
+func TestConnector_Authenticate(t *testing.T) {
+	const content = "p@ssw0rd,alice@example.com,alice,\"admin,dev\"\nhunter2,bob@example.com,bob,\n"
+
+	tests := map[string]struct {
+		username string
+		password string
+		want     authn.Identity
+		wantErr  bool
+	}{
+		"MatchWithGroups": {
+			username: "alice@example.com",
+			password: "p@ssw0rd",
+			want:     authn.Identity{Subject: "alice", Email: "alice@example.com", Groups: []string{"admin", "dev"}},
+		},
+		"MatchWithoutGroups": {
+			username: "bob@example.com",
+			password: "hunter2",
+			want:     authn.Identity{Subject: "bob", Email: "bob@example.com"},
+		},
+		"WrongPassword": {
+			username: "alice@example.com",
+			password: "wrong",
+			wantErr:  true,
+		},
+		"UnknownUsername": {
+			username: "carol@example.com",
+			password: "p@ssw0rd",
+			wantErr:  true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := New(writeFile(t, content))
+			got, err := c.Authenticate(context.TODO(), tc.username, tc.password)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() returned an error: %s", err)
+			}
+			if got.Subject != tc.want.Subject || got.Email != tc.want.Email || len(got.Groups) != len(tc.want.Groups) {
+				t.Errorf("Identity = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConnector_Authenticate_FileNotFound(t *testing.T) {
+	c := New(filepath.Join(t.TempDir(), "does-not-exist.csv"))
+	if _, err := c.Authenticate(context.TODO(), "alice@example.com", "p@ssw0rd"); err == nil {
+		t.Fatalf("expected an error but got none")
+	}
+}
@@ -0,0 +1,74 @@
+package authn
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// synthesizedTokenKey signs ID tokens minted by Synthesize. Connectors such
+// as ldap and staticpasswords verify a credential against a directory that
+// is not the OIDC issuer itself, so there is no issuer-signed token to
+// forward; the key only needs to be internally consistent for the lifetime
+// of this process; kubelogin never verifies these tokens itself, and the
+// apiserver's OIDC webhook is expected to trust the issuer named in the
+// token, not kubelogin's signature.
+var synthesizedTokenKey = func() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}()
+
+// claims mirrors the subset of OIDC ID token claims that downstream
+// consumers (the Kubernetes apiserver's OIDC authenticator) map onto a
+// user's identity and groups.
+type claims struct {
+	Issuer   string   `json:"iss"`
+	Subject  string   `json:"sub"`
+	Audience string   `json:"aud"`
+	Email    string   `json:"email,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+	IssuedAt int64    `json:"iat"`
+	Expiry   int64    `json:"exp"`
+}
+
+// Synthesize mints a locally-signed, HS256 JWT carrying identity's claims,
+// for connectors that verify a credential out-of-band from the OIDC
+// issuer's own token endpoint. The token is valid for ttl.
+func Synthesize(identity Identity, issuerURL, audience string, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiry := now.Add(ttl)
+	c := claims{
+		Issuer:   issuerURL,
+		Subject:  identity.Subject,
+		Audience: audience,
+		Email:    identity.Email,
+		Groups:   identity.Groups,
+		IssuedAt: now.Unix(),
+		Expiry:   expiry.Unix(),
+	}
+	header := map[string]string{"alg": "HS256", "typ": "JWT"}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", time.Time{}, xerrors.Errorf("could not marshal the JWT header: %w", err)
+	}
+	claimsJSON, err := json.Marshal(c)
+	if err != nil {
+		return "", time.Time{}, xerrors.Errorf("could not marshal the JWT claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	mac := hmac.New(sha256.New, synthesizedTokenKey)
+	mac.Write([]byte(signingInput))
+	signature := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	return signingInput + "." + signature, expiry, nil
+}
@@ -3,10 +3,13 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/google/wire"
 	"github.com/int128/kubelogin/pkg/adaptors"
+	"github.com/int128/kubelogin/pkg/adaptors/tlsconfig"
 	"github.com/int128/kubelogin/pkg/models/kubeconfig"
 	"github.com/int128/kubelogin/pkg/usecases"
 	"github.com/spf13/cobra"
@@ -30,14 +33,16 @@ const examples = `  # Login to the provider using the authorization code flow.
   # Run as a credential plugin.
   %[1]s get-token --oidc-issuer-url=https://issuer.example.com`
 
-var defaultListenPort = []int{8000, 18000}
+var defaultListenAddress = []string{"127.0.0.1:8000", "127.0.0.1:18000"}
 var defaultTokenCacheDir = homedir.HomeDir() + "/.kube/cache/oidc-login"
 
 // Cmd provides interaction with command line interface (CLI).
 type Cmd struct {
-	Login    usecases.Login
-	GetToken usecases.GetToken
-	Logger   adaptors.Logger
+	Login              usecases.Login
+	GetToken           usecases.GetToken
+	GenerateKubeconfig usecases.GenerateKubeconfig
+	Logger             adaptors.Logger
+	PasswordReader     adaptors.PasswordReader
 }
 
 // Run parses the command line arguments and executes the specified use-case.
@@ -53,6 +58,9 @@ func (cmd *Cmd) Run(ctx context.Context, args []string, version string) int {
 	getTokenCmd := newGetTokenCmd(ctx, cmd)
 	rootCmd.AddCommand(getTokenCmd)
 
+	kubeconfigCmd := newKubeconfigCmd(ctx, executable, cmd)
+	rootCmd.AddCommand(kubeconfigCmd)
+
 	versionCmd := &cobra.Command{
 		Use:   "version",
 		Short: "Print the version information",
@@ -92,20 +100,158 @@ func (o *kubectlOptions) register(f *pflag.FlagSet) {
 	f.IntVarP(&o.Verbose, "v", "v", 0, "If set to 1 or greater, it shows debug log")
 }
 
+// tlsProfileOptions represents the options that select and override a TLS
+// profile (see pkg/adaptors/tlsconfig) for a single outbound HTTPS client.
+type tlsProfileOptions struct {
+	Profile      string
+	MinVersion   string
+	CipherSuites []string
+}
+
+// register adds the flags to f. If left unset, Profile defaults to
+// tlsconfig.ProfileSecure for the connection to the Kubernetes API server
+// and to tlsconfig.ProfileDefault for OIDC discovery and token exchange;
+// an explicit --tls-profile overrides both.
+func (o *tlsProfileOptions) register(f *pflag.FlagSet) {
+	f.StringVar(&o.Profile, "tls-profile", "", "TLS profile to use for outbound HTTPS. One of: secure, default, legacy (default \"secure\" for the API server, \"default\" for the OIDC provider)")
+	f.StringVar(&o.MinVersion, "tls-min-version", "", "Override the minimum TLS version of the profile, e.g. 1.2")
+	f.StringSliceVar(&o.CipherSuites, "tls-cipher-suites", nil, "Override the cipher suites of the profile")
+}
+
+// forAPIServer resolves the options for the Kubernetes API server client,
+// defaulting to tlsconfig.ProfileSecure when the user did not set a profile.
+func (o tlsProfileOptions) forAPIServer() usecases.TLSOptions {
+	profile := tlsconfig.Profile(o.Profile)
+	if profile == "" {
+		profile = tlsconfig.ProfileSecure
+	}
+	return usecases.TLSOptions{Profile: profile, MinVersion: o.MinVersion, CipherSuites: o.CipherSuites}
+}
+
+// forOIDC resolves the options for the OIDC issuer client, defaulting to
+// tlsconfig.ProfileDefault when the user did not set a profile.
+func (o tlsProfileOptions) forOIDC() usecases.TLSOptions {
+	profile := tlsconfig.Profile(o.Profile)
+	if profile == "" {
+		profile = tlsconfig.ProfileDefault
+	}
+	return usecases.TLSOptions{Profile: profile, MinVersion: o.MinVersion, CipherSuites: o.CipherSuites}
+}
+
 // loginOptions represents the options for Login use-case.
 type loginOptions struct {
-	ListenPort      []int
-	SkipOpenBrowser bool
-	Username        string
-	Password        string
+	ListenPort          []int
+	ListenAddress       []string
+	RedirectURLHostname string
+	SkipOpenBrowser     bool
+	Username            string
+	Password            string
+	PasswordStdin       bool
+	PasswordCommand     string
+	authConnectorOptions
+	tls tlsProfileOptions
 }
 
 func (o *loginOptions) register(f *pflag.FlagSet) {
 	f.SortFlags = false
-	f.IntSliceVar(&o.ListenPort, "listen-port", defaultListenPort, "Port to bind to the local server. If multiple ports are given, it will try the ports in order")
+	f.IntSliceVar(&o.ListenPort, "listen-port", nil, "(Deprecated) Port to bind to the local server. Use --listen-address instead")
+	f.StringArrayVar(&o.ListenAddress, "listen-address", nil, "Address to bind to the local server, e.g. 127.0.0.1:8000 or unix:/path/to/socket. If multiple are given, it will try them in order. Defaults to 127.0.0.1:8000 and 127.0.0.1:18000")
+	f.StringVar(&o.RedirectURLHostname, "redirect-url-hostname", "localhost", "Hostname to advertise as the OIDC redirect_uri, independent of --listen-address")
 	f.BoolVar(&o.SkipOpenBrowser, "skip-open-browser", false, "If true, it does not open the browser on authentication")
 	f.StringVar(&o.Username, "username", "", "If set, perform the resource owner password credentials grant")
 	f.StringVar(&o.Password, "password", "", "If set, use the password instead of asking it")
+	f.BoolVar(&o.PasswordStdin, "password-stdin", false, "If set, read the password from stdin instead of the terminal, even if stdin is a TTY")
+	f.StringVar(&o.PasswordCommand, "password-command", "", "If set, execute the command and use its stdout as the password, e.g. \"pass show kubelogin\"")
+	o.authConnectorOptions.register(f)
+	o.tls.register(f)
+}
+
+// resolveListenAddrs returns the addresses usecases.LoginIn/GetTokenIn
+// should bind the loopback redirect server to. --listen-port is
+// deprecated in favor of --listen-address but is still honored, by
+// expanding each port into a "127.0.0.1:<port>" address and placing it
+// ahead of --listen-address. defaultListenAddress only applies when
+// neither flag was set, so an explicit --listen-port is not silently
+// padded out with the ports it was meant to replace.
+func (o *loginOptions) resolveListenAddrs() []string {
+	if len(o.ListenPort) == 0 && len(o.ListenAddress) == 0 {
+		return defaultListenAddress
+	}
+	if len(o.ListenPort) == 0 {
+		return o.ListenAddress
+	}
+	addrs := make([]string, 0, len(o.ListenPort)+len(o.ListenAddress))
+	for _, port := range o.ListenPort {
+		addrs = append(addrs, fmt.Sprintf("127.0.0.1:%d", port))
+	}
+	return append(addrs, o.ListenAddress...)
+}
+
+// resolvePassword returns the password to use for the resource owner
+// password credentials grant. It is a no-op unless --username is set, so
+// that --password-stdin or --password-command left set from a previous
+// invocation does not block on stdin or exec a command during an
+// unrelated authorization-code login. --password takes precedence,
+// followed by --password-command, followed by --password-stdin;
+// otherwise it falls back to prompting on the terminal, or reading a
+// line from stdin if stdin is not a TTY.
+func (o *loginOptions) resolvePassword(r adaptors.PasswordReader) (string, error) {
+	if o.Username == "" {
+		return "", nil
+	}
+	switch {
+	case o.Password != "":
+		return o.Password, nil
+	case o.PasswordCommand != "":
+		password, err := r.ReadPasswordFromCommand(o.PasswordCommand)
+		if err != nil {
+			return "", xerrors.Errorf("could not read the password from --password-command: %w", err)
+		}
+		return password, nil
+	case o.PasswordStdin:
+		password, err := r.ReadPasswordFromStdin()
+		if err != nil {
+			return "", xerrors.Errorf("could not read the password from stdin: %w", err)
+		}
+		return password, nil
+	default:
+		password, err := r.ReadPassword()
+		if err != nil {
+			return "", xerrors.Errorf("could not read the password: %w", err)
+		}
+		return password, nil
+	}
+}
+
+// authConnectorOptions represents the options for an authn connector, used
+// by the resource owner password credentials grant when the issuer itself
+// does not support ROPC.
+type authConnectorOptions struct {
+	AuthConnector          string
+	LDAPHost               string
+	LDAPBindDN             string
+	LDAPBindPassword       string
+	LDAPUserSearchBaseDN   string
+	LDAPUserSearchFilter   string
+	LDAPGroupSearchBaseDN  string
+	LDAPGroupSearchFilter  string
+	LDAPGroupAttribute     string
+	LDAPInsecureSkipVerify bool
+	StaticPasswordsFile    string
+}
+
+func (o *authConnectorOptions) register(f *pflag.FlagSet) {
+	f.StringVar(&o.AuthConnector, "auth-connector", "oidc-ropc", "Connector to use for the resource owner password credentials grant. One of: oidc-ropc, ldap, static-passwords")
+	f.StringVar(&o.LDAPHost, "ldap-host", "", "LDAP server address, e.g. ldaps://ldap.example.com:636")
+	f.StringVar(&o.LDAPBindDN, "ldap-bind-dn", "", "DN of the service account to bind as before searching for the user")
+	f.StringVar(&o.LDAPBindPassword, "ldap-bind-password", os.Getenv("KUBELOGIN_LDAP_BIND_PASSWORD"), "Password of the service account. Defaults to KUBELOGIN_LDAP_BIND_PASSWORD")
+	f.StringVar(&o.LDAPUserSearchBaseDN, "ldap-user-search-base-dn", "", "Base DN to search for the user")
+	f.StringVar(&o.LDAPUserSearchFilter, "ldap-user-search-filter", "(uid=%s)", "Filter to search for the user. %s is replaced by the username")
+	f.StringVar(&o.LDAPGroupSearchBaseDN, "ldap-group-search-base-dn", "", "Base DN to search for the user's groups")
+	f.StringVar(&o.LDAPGroupSearchFilter, "ldap-group-search-filter", "(member=%s)", "Filter to search for the user's groups. %s is replaced by the user DN")
+	f.StringVar(&o.LDAPGroupAttribute, "ldap-group-attribute", "cn", "Attribute of a group entry to map onto the OIDC groups claim")
+	f.BoolVar(&o.LDAPInsecureSkipVerify, "ldap-insecure-skip-verify", false, "If true, the LDAP server's certificate will not be checked for validity. This will make your LDAPS connection insecure")
+	f.StringVar(&o.StaticPasswordsFile, "static-passwords-file", os.Getenv("KUBELOGIN_STATIC_PASSWORDS_FILE"), "Path to a CSV file of password,email,subject,groups entries. Defaults to KUBELOGIN_STATIC_PASSWORDS_FILE")
 }
 
 func newRootCmd(ctx context.Context, executable string, cmd *Cmd) *cobra.Command {
@@ -120,16 +266,36 @@ func newRootCmd(ctx context.Context, executable string, cmd *Cmd) *cobra.Command
 		Args:    cobra.NoArgs,
 		RunE: func(*cobra.Command, []string) error {
 			cmd.Logger.SetLevel(adaptors.LogLevel(o.Verbose))
+			password, err := o.loginOptions.resolvePassword(cmd.PasswordReader)
+			if err != nil {
+				return xerrors.Errorf("error: %w", err)
+			}
 			in := usecases.LoginIn{
-				KubeconfigFilename: o.Kubeconfig,
-				KubeconfigContext:  kubeconfig.ContextName(o.Context),
-				KubeconfigUser:     kubeconfig.UserName(o.User),
-				CACertFilename:     o.CertificateAuthority,
-				SkipTLSVerify:      o.SkipTLSVerify,
-				ListenPort:         o.ListenPort,
-				SkipOpenBrowser:    o.SkipOpenBrowser,
-				Username:           o.Username,
-				Password:           o.Password,
+				KubeconfigFilename:  o.Kubeconfig,
+				KubeconfigContext:   kubeconfig.ContextName(o.Context),
+				KubeconfigUser:      kubeconfig.UserName(o.User),
+				CACertFilename:      o.CertificateAuthority,
+				SkipTLSVerify:       o.SkipTLSVerify,
+				APIServerTLS:        o.tls.forAPIServer(),
+				OIDCTLS:             o.tls.forOIDC(),
+				ListenAddrs:         o.loginOptions.resolveListenAddrs(),
+				RedirectURLHostname: o.RedirectURLHostname,
+				SkipOpenBrowser:     o.SkipOpenBrowser,
+				Username:            o.Username,
+				Password:            password,
+				AuthConnector:       o.AuthConnector,
+				LDAP: usecases.LDAPOptions{
+					Host:               o.LDAPHost,
+					BindDN:             o.LDAPBindDN,
+					BindPassword:       o.LDAPBindPassword,
+					UserSearchBaseDN:   o.LDAPUserSearchBaseDN,
+					UserSearchFilter:   o.LDAPUserSearchFilter,
+					GroupSearchBaseDN:  o.LDAPGroupSearchBaseDN,
+					GroupSearchFilter:  o.LDAPGroupSearchFilter,
+					GroupAttribute:     o.LDAPGroupAttribute,
+					InsecureSkipVerify: o.LDAPInsecureSkipVerify,
+				},
+				StaticPasswordsFile: o.StaticPasswordsFile,
 			}
 			if err := cmd.Login.Do(ctx, in); err != nil {
 				return xerrors.Errorf("error: %w", err)
@@ -145,14 +311,16 @@ func newRootCmd(ctx context.Context, executable string, cmd *Cmd) *cobra.Command
 // getTokenOptions represents the options for get-token command.
 type getTokenOptions struct {
 	loginOptions
-	IssuerURL            string
-	ClientID             string
-	ClientSecret         string
-	ExtraScopes          []string
-	CertificateAuthority string
-	SkipTLSVerify        bool
-	Verbose              int
-	TokenCacheDir        string
+	IssuerURL             string
+	ClientID              string
+	ClientSecret          string
+	ExtraScopes           []string
+	CertificateAuthority  string
+	SkipTLSVerify         bool
+	Verbose               int
+	TokenCacheDir         string
+	TokenCacheLockTimeout time.Duration
+	DisableTokenCacheLock bool
 }
 
 func (o *getTokenOptions) register(f *pflag.FlagSet) {
@@ -166,6 +334,8 @@ func (o *getTokenOptions) register(f *pflag.FlagSet) {
 	f.BoolVar(&o.SkipTLSVerify, "insecure-skip-tls-verify", false, "If true, the server's certificate will not be checked for validity. This will make your HTTPS connections insecure")
 	f.IntVarP(&o.Verbose, "v", "v", 0, "If set to 1 or greater, it shows debug log")
 	f.StringVar(&o.TokenCacheDir, "token-cache-dir", defaultTokenCacheDir, "Path to a directory for caching tokens")
+	f.DurationVar(&o.TokenCacheLockTimeout, "token-cache-lock-timeout", 30*time.Second, "Time to wait for another process to finish writing the token cache before giving up")
+	f.BoolVar(&o.DisableTokenCacheLock, "disable-token-cache-lock", false, "If true, do not serialize concurrent get-token invocations on the token cache")
 }
 
 func newGetTokenCmd(ctx context.Context, cmd *Cmd) *cobra.Command {
@@ -187,18 +357,39 @@ func newGetTokenCmd(ctx context.Context, cmd *Cmd) *cobra.Command {
 		},
 		RunE: func(*cobra.Command, []string) error {
 			cmd.Logger.SetLevel(adaptors.LogLevel(o.Verbose))
+			password, err := o.loginOptions.resolvePassword(cmd.PasswordReader)
+			if err != nil {
+				return xerrors.Errorf("error: %w", err)
+			}
 			in := usecases.GetTokenIn{
-				IssuerURL:       o.IssuerURL,
-				ClientID:        o.ClientID,
-				ClientSecret:    o.ClientSecret,
-				ExtraScopes:     o.ExtraScopes,
-				CACertFilename:  o.CertificateAuthority,
-				SkipTLSVerify:   o.SkipTLSVerify,
-				ListenPort:      o.ListenPort,
-				SkipOpenBrowser: o.SkipOpenBrowser,
-				Username:        o.Username,
-				Password:        o.Password,
-				TokenCacheDir:   o.TokenCacheDir,
+				IssuerURL:             o.IssuerURL,
+				ClientID:              o.ClientID,
+				ClientSecret:          o.ClientSecret,
+				ExtraScopes:           o.ExtraScopes,
+				CACertFilename:        o.CertificateAuthority,
+				SkipTLSVerify:         o.SkipTLSVerify,
+				ListenAddrs:           o.loginOptions.resolveListenAddrs(),
+				RedirectURLHostname:   o.RedirectURLHostname,
+				SkipOpenBrowser:       o.SkipOpenBrowser,
+				Username:              o.Username,
+				Password:              password,
+				TokenCacheDir:         o.TokenCacheDir,
+				TokenCacheLockTimeout: o.TokenCacheLockTimeout,
+				DisableTokenCacheLock: o.DisableTokenCacheLock,
+				OIDCTLS:               o.tls.forOIDC(),
+				AuthConnector:         o.AuthConnector,
+				LDAP: usecases.LDAPOptions{
+					Host:               o.LDAPHost,
+					BindDN:             o.LDAPBindDN,
+					BindPassword:       o.LDAPBindPassword,
+					UserSearchBaseDN:   o.LDAPUserSearchBaseDN,
+					UserSearchFilter:   o.LDAPUserSearchFilter,
+					GroupSearchBaseDN:  o.LDAPGroupSearchBaseDN,
+					GroupSearchFilter:  o.LDAPGroupSearchFilter,
+					GroupAttribute:     o.LDAPGroupAttribute,
+					InsecureSkipVerify: o.LDAPInsecureSkipVerify,
+				},
+				StaticPasswordsFile: o.StaticPasswordsFile,
 			}
 			if err := cmd.GetToken.Do(ctx, in); err != nil {
 				return xerrors.Errorf("error: %w", err)
@@ -208,4 +399,80 @@ func newGetTokenCmd(ctx context.Context, cmd *Cmd) *cobra.Command {
 	}
 	o.register(c.Flags())
 	return c
-}
\ No newline at end of file
+}
+
+// kubeconfigOptions represents the options for the kubeconfig command.
+type kubeconfigOptions struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	ExtraScopes  []string
+	Cluster      string
+	Context      string
+	User         string
+	Output       string
+}
+
+func (o *kubeconfigOptions) register(f *pflag.FlagSet) {
+	f.SortFlags = false
+	f.StringVar(&o.IssuerURL, "oidc-issuer-url", "", "Issuer URL of the provider (mandatory)")
+	f.StringVar(&o.ClientID, "oidc-client-id", "", "Client ID of the provider (mandatory)")
+	f.StringVar(&o.ClientSecret, "oidc-client-secret", "", "Client secret of the provider")
+	f.StringSliceVar(&o.ExtraScopes, "oidc-extra-scope", nil, "Scopes to request to the provider")
+	f.StringVar(&o.Cluster, "cluster", "", "Name of the cluster in the generated kubeconfig (mandatory)")
+	f.StringVar(&o.Context, "context", "", "Name of the context in the generated kubeconfig. Defaults to --cluster")
+	f.StringVar(&o.User, "user", "", "Name of the user in the generated kubeconfig. Defaults to --cluster")
+	f.StringVar(&o.Output, "output", "", "Path to write the kubeconfig to. Defaults to stdout")
+}
+
+// newKubeconfigCmd returns the kubeconfig command, which prints (or
+// patches an existing kubeconfig with) a users[].exec block that invokes
+// `executable get-token` with the flags this binary actually supports,
+// so that operators do not have to hand-edit a kubeconfig to wire up the
+// credential plugin.
+func newKubeconfigCmd(ctx context.Context, executable string, cmd *Cmd) *cobra.Command {
+	var o kubeconfigOptions
+	var ko kubectlOptions
+	c := &cobra.Command{
+		Use:   "kubeconfig [flags]",
+		Short: "Generate a kubeconfig wiring the exec credential plugin",
+		Args: func(c *cobra.Command, args []string) error {
+			if err := cobra.NoArgs(c, args); err != nil {
+				return err
+			}
+			if o.IssuerURL == "" {
+				return xerrors.New("--oidc-issuer-url is missing")
+			}
+			if o.ClientID == "" {
+				return xerrors.New("--oidc-client-id is missing")
+			}
+			if o.Cluster == "" {
+				return xerrors.New("--cluster is missing")
+			}
+			return nil
+		},
+		RunE: func(*cobra.Command, []string) error {
+			in := usecases.GenerateKubeconfigIn{
+				Executable:         executable,
+				KubeconfigFilename: ko.Kubeconfig,
+				Cluster:            o.Cluster,
+				Context:            o.Context,
+				User:               o.User,
+				Output:             o.Output,
+				IssuerURL:          o.IssuerURL,
+				ClientID:           o.ClientID,
+				ClientSecret:       o.ClientSecret,
+				ExtraScopes:        o.ExtraScopes,
+			}
+			if err := cmd.GenerateKubeconfig.Do(ctx, in); err != nil {
+				return xerrors.Errorf("error: %w", err)
+			}
+			return nil
+		},
+	}
+	f := c.Flags()
+	f.SortFlags = false
+	f.StringVar(&ko.Kubeconfig, "kubeconfig", "", "Path to an existing kubeconfig to patch instead of writing a standalone one")
+	o.register(f)
+	return c
+}
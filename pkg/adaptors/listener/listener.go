@@ -0,0 +1,31 @@
+// Package listener binds the loopback redirect server used by the
+// authorization code flow, accepting a TCP address, an IPv6 address, or
+// a "unix:/path/to/socket" form so that the listening socket can be
+// restricted by filesystem permissions on multi-tenant hosts.
+package listener
+
+import (
+	"net"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// Listen binds addr and returns the resulting net.Listener.
+//
+// addr may be a TCP address such as "127.0.0.1:0" or "[::1]:0", or a Unix
+// domain socket in the form "unix:/path/to/socket".
+func Listen(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		l, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, xerrors.Errorf("could not listen on the unix socket %s: %w", path, err)
+		}
+		return l, nil
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, xerrors.Errorf("could not listen on %s: %w", addr, err)
+	}
+	return l, nil
+}
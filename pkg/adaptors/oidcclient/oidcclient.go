@@ -0,0 +1,158 @@
+// Package oidcclient implements the minimal subset of an OIDC relying
+// party that kubelogin needs: discovery, the resource owner password
+// credentials grant, and the authorization code grant, all performed over
+// an *http.Client whose *tls.Config was built from a tlsconfig.Options
+// profile so that the TLS posture of these calls is configurable.
+package oidcclient
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// Client is an OIDC relying party client for a single issuer.
+type Client struct {
+	HTTPClient *http.Client
+	IssuerURL  string
+}
+
+// New returns a Client that performs TLS with tlsConfig. A nil tlsConfig
+// uses the Go defaults.
+func New(issuerURL string, tlsConfig *tls.Config) *Client {
+	return &Client{
+		HTTPClient: &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}},
+		IssuerURL:  issuerURL,
+	}
+}
+
+// discoveryDocument is the subset of the OIDC discovery document kubelogin
+// needs to locate the token and authorization endpoints.
+type discoveryDocument struct {
+	TokenEndpoint         string `json:"token_endpoint"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+}
+
+// Discover fetches and parses {IssuerURL}/.well-known/openid-configuration.
+func (c *Client) Discover(ctx context.Context) (*discoveryDocument, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.IssuerURL, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, xerrors.Errorf("could not create the discovery request: %w", err)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, xerrors.Errorf("could not fetch the discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, xerrors.Errorf("discovery endpoint returned status %d", resp.StatusCode)
+	}
+	var doc discoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, xerrors.Errorf("could not parse the discovery document: %w", err)
+	}
+	return &doc, nil
+}
+
+// tokenResponse is the subset of a token endpoint response kubelogin uses.
+type tokenResponse struct {
+	IDToken string `json:"id_token"`
+}
+
+func (c *Client) postForm(ctx context.Context, tokenEndpoint string, form url.Values) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", xerrors.Errorf("could not create the token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", xerrors.Errorf("could not send the token request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", xerrors.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+	var tr tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return "", xerrors.Errorf("could not parse the token response: %w", err)
+	}
+	if tr.IDToken == "" {
+		return "", xerrors.New("token response did not contain an id_token")
+	}
+	return tr.IDToken, nil
+}
+
+// ExchangeROPC performs the resource owner password credentials grant and
+// returns the resulting ID token.
+func (c *Client) ExchangeROPC(ctx context.Context, clientID, clientSecret, username, password string) (string, error) {
+	doc, err := c.Discover(ctx)
+	if err != nil {
+		return "", xerrors.Errorf("could not discover the token endpoint: %w", err)
+	}
+	form := url.Values{
+		"grant_type": {"password"},
+		"client_id":  {clientID},
+		"username":   {username},
+		"password":   {password},
+	}
+	if clientSecret != "" {
+		form.Set("client_secret", clientSecret)
+	}
+	idToken, err := c.postForm(ctx, doc.TokenEndpoint, form)
+	if err != nil {
+		return "", xerrors.Errorf("could not exchange the password for a token: %w", err)
+	}
+	return idToken, nil
+}
+
+// ExchangeCode performs the authorization code grant and returns the
+// resulting ID token.
+func (c *Client) ExchangeCode(ctx context.Context, clientID, clientSecret, code, redirectURI string) (string, error) {
+	doc, err := c.Discover(ctx)
+	if err != nil {
+		return "", xerrors.Errorf("could not discover the token endpoint: %w", err)
+	}
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"client_id":    {clientID},
+		"code":         {code},
+		"redirect_uri": {redirectURI},
+	}
+	if clientSecret != "" {
+		form.Set("client_secret", clientSecret)
+	}
+	idToken, err := c.postForm(ctx, doc.TokenEndpoint, form)
+	if err != nil {
+		return "", xerrors.Errorf("could not exchange the code for a token: %w", err)
+	}
+	return idToken, nil
+}
+
+// AuthCodeURL returns the URL to redirect the user's browser to in order to
+// start the authorization code grant.
+func (c *Client) AuthCodeURL(ctx context.Context, clientID, redirectURI, state string, extraScopes []string) (string, error) {
+	doc, err := c.Discover(ctx)
+	if err != nil {
+		return "", xerrors.Errorf("could not discover the authorization endpoint: %w", err)
+	}
+	scopes := append([]string{"openid"}, extraScopes...)
+	v := url.Values{
+		"response_type": {"code"},
+		"client_id":     {clientID},
+		"redirect_uri":  {redirectURI},
+		"state":         {state},
+		"scope":         {strings.Join(scopes, " ")},
+	}
+	u, err := url.Parse(doc.AuthorizationEndpoint)
+	if err != nil {
+		return "", xerrors.Errorf("could not parse the authorization endpoint: %w", err)
+	}
+	u.RawQuery = v.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,16 @@
+package adaptors
+
+//go:generate mockgen -destination mock_adaptors/mock_password_reader.go github.com/int128/kubelogin/pkg/adaptors PasswordReader
+
+// PasswordReader reads a password without it appearing on the command
+// line or in a process listing.
+type PasswordReader interface {
+	// ReadPassword prompts for a password on the terminal if stdin is a
+	// TTY, and otherwise reads a single line from stdin.
+	ReadPassword() (string, error)
+	// ReadPasswordFromStdin reads a single line from stdin unconditionally.
+	ReadPasswordFromStdin() (string, error)
+	// ReadPasswordFromCommand executes command and returns its trimmed
+	// stdout.
+	ReadPasswordFromCommand(command string) (string, error)
+}
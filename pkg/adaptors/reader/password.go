@@ -0,0 +1,68 @@
+// Package reader provides adaptors.PasswordReader backed by the real
+// terminal and stdin of the process.
+package reader
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/google/wire"
+	"github.com/int128/kubelogin/pkg/adaptors"
+	"golang.org/x/term"
+	"golang.org/x/xerrors"
+)
+
+// Set provides an implementation and interface for PasswordReader.
+var Set = wire.NewSet(
+	wire.Struct(new(PasswordReader), "*"),
+	wire.Bind(new(adaptors.PasswordReader), new(*PasswordReader)),
+)
+
+// PasswordReader implements adaptors.PasswordReader.
+type PasswordReader struct {
+	Stdin io.Reader
+}
+
+// ReadPassword prompts on the terminal with echo disabled when stdin is a
+// TTY (so that the password is not recorded in shell history or visible
+// on screen), falling back to reading one line from stdin so that a
+// password can be piped in from a secret manager.
+func (r *PasswordReader) ReadPassword() (string, error) {
+	if f, ok := r.Stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		print("Password: ")
+		b, err := term.ReadPassword(int(f.Fd()))
+		println()
+		if err != nil {
+			return "", xerrors.Errorf("could not read the password from the terminal: %w", err)
+		}
+		return string(b), nil
+	}
+	return r.ReadPasswordFromStdin()
+}
+
+// ReadPasswordFromStdin reads a single line from stdin.
+func (r *PasswordReader) ReadPasswordFromStdin() (string, error) {
+	s := bufio.NewScanner(r.Stdin)
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			return "", xerrors.Errorf("could not read a line from stdin: %w", err)
+		}
+		return "", xerrors.New("stdin is empty")
+	}
+	return s.Text(), nil
+}
+
+// ReadPasswordFromCommand executes command via the shell and returns its
+// trimmed stdout, for helpers such as `pass`, `op` or `vault read`.
+func (r *PasswordReader) ReadPasswordFromCommand(command string) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", xerrors.Errorf("could not execute the password command: %w", err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
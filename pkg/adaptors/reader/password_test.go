@@ -0,0 +1,84 @@
+package reader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordReader_ReadPasswordFromStdin(t *testing.T) {
+	tests := map[string]struct {
+		stdin   string
+		want    string
+		wantErr bool
+	}{
+		"SingleLine": {
+			stdin: "hello\n",
+			want:  "hello",
+		},
+		"NoTrailingNewline": {
+			stdin: "hello",
+			want:  "hello",
+		},
+		"OnlyFirstLine": {
+			stdin: "hello\nworld\n",
+			want:  "hello",
+		},
+		"Empty": {
+			stdin:   "",
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := &PasswordReader{Stdin: strings.NewReader(tc.stdin)}
+			got, err := r.ReadPasswordFromStdin()
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadPasswordFromStdin() returned an error: %s", err)
+			}
+			if got != tc.want {
+				t.Errorf("password = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPasswordReader_ReadPasswordFromCommand(t *testing.T) {
+	tests := map[string]struct {
+		command string
+		want    string
+		wantErr bool
+	}{
+		"TrimsTrailingNewline": {
+			command: "echo hello",
+			want:    "hello",
+		},
+		"CommandFails": {
+			command: "exit 1",
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := &PasswordReader{}
+			got, err := r.ReadPasswordFromCommand(tc.command)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadPasswordFromCommand() returned an error: %s", err)
+			}
+			if got != tc.want {
+				t.Errorf("password = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
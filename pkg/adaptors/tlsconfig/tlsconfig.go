@@ -0,0 +1,111 @@
+// Package tlsconfig builds a *tls.Config from the --tls-profile,
+// --tls-min-version and --tls-cipher-suites flags shared by the OIDC
+// issuer client and the Kubernetes API server client.
+package tlsconfig
+
+import (
+	"crypto/tls"
+
+	"golang.org/x/xerrors"
+)
+
+// Profile names a named preset of TLS settings.
+type Profile string
+
+// Supported profiles.
+const (
+	// ProfileSecure pins TLS 1.3 only.
+	ProfileSecure Profile = "secure"
+	// ProfileDefault requires TLS 1.2+ and restricts cipher suites to the
+	// modern AEAD set.
+	ProfileDefault Profile = "default"
+	// ProfileLegacy preserves the Go standard library defaults.
+	ProfileLegacy Profile = "legacy"
+)
+
+// modernCipherSuites is the AEAD-only set allowed by ProfileDefault:
+// ECDHE with AES-GCM or ChaCha20-Poly1305.
+var modernCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+}
+
+var modernCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384}
+
+// Options represents the flags that configure a TLS profile, with
+// MinVersion and CipherSuites acting as explicit overrides of the preset
+// chosen by Profile.
+type Options struct {
+	Profile      Profile
+	MinVersion   string
+	CipherSuites []string
+}
+
+// New returns a *tls.Config for the given profile, applying MinVersion and
+// CipherSuites as overrides on top of the preset.
+func New(o Options) (*tls.Config, error) {
+	c := &tls.Config{}
+	switch o.Profile {
+	case ProfileSecure:
+		c.MinVersion = tls.VersionTLS13
+	case ProfileDefault, "":
+		c.MinVersion = tls.VersionTLS12
+		c.CipherSuites = modernCipherSuites
+		c.CurvePreferences = modernCurves
+	case ProfileLegacy:
+		// Leave the Go defaults untouched.
+	default:
+		return nil, xerrors.Errorf("invalid tls profile: %s", o.Profile)
+	}
+
+	if o.MinVersion != "" {
+		v, err := parseVersion(o.MinVersion)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid tls-min-version: %w", err)
+		}
+		c.MinVersion = v
+	}
+	if len(o.CipherSuites) > 0 {
+		suites, err := parseCipherSuites(o.CipherSuites)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid tls-cipher-suites: %w", err)
+		}
+		c.CipherSuites = suites
+	}
+	return c, nil
+}
+
+func parseVersion(s string) (uint16, error) {
+	switch s {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, xerrors.Errorf("unknown TLS version: %s", s)
+	}
+}
+
+func parseCipherSuites(names []string) ([]uint16, error) {
+	available := make(map[string]uint16)
+	for _, cs := range tls.CipherSuites() {
+		available[cs.Name] = cs.ID
+	}
+	var ids []uint16
+	for _, name := range names {
+		id, ok := available[name]
+		if !ok {
+			return nil, xerrors.Errorf("unknown cipher suite: %s", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
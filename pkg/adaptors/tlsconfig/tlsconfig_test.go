@@ -0,0 +1,75 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := map[string]struct {
+		in         Options
+		wantMinVer uint16
+		wantSuites []uint16
+		wantErr    bool
+	}{
+		"Secure": {
+			in:         Options{Profile: ProfileSecure},
+			wantMinVer: tls.VersionTLS13,
+		},
+		"Default": {
+			in:         Options{Profile: ProfileDefault},
+			wantMinVer: tls.VersionTLS12,
+			wantSuites: modernCipherSuites,
+		},
+		"EmptyProfileIsDefault": {
+			in:         Options{},
+			wantMinVer: tls.VersionTLS12,
+			wantSuites: modernCipherSuites,
+		},
+		"Legacy": {
+			in:         Options{Profile: ProfileLegacy},
+			wantMinVer: 0,
+		},
+		"InvalidProfile": {
+			in:      Options{Profile: "bogus"},
+			wantErr: true,
+		},
+		"MinVersionOverride": {
+			in:         Options{Profile: ProfileSecure, MinVersion: "1.2"},
+			wantMinVer: tls.VersionTLS12,
+		},
+		"InvalidMinVersion": {
+			in:      Options{Profile: ProfileSecure, MinVersion: "bogus"},
+			wantErr: true,
+		},
+		"CipherSuitesOverride": {
+			in:         Options{Profile: ProfileDefault, CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}},
+			wantMinVer: tls.VersionTLS12,
+			wantSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		},
+		"InvalidCipherSuite": {
+			in:      Options{Profile: ProfileDefault, CipherSuites: []string{"bogus"}},
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := New(tc.in)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() returned an error: %s", err)
+			}
+			if got.MinVersion != tc.wantMinVer {
+				t.Errorf("MinVersion = %#x, want %#x", got.MinVersion, tc.wantMinVer)
+			}
+			if tc.wantSuites != nil && len(got.CipherSuites) != len(tc.wantSuites) {
+				t.Errorf("CipherSuites = %v, want %v", got.CipherSuites, tc.wantSuites)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+// Package tokencache provides a Locker that serializes concurrent
+// get-token invocations against the same issuer and client, so that
+// many kubectl processes started at once do not each race to
+// authenticate and write the token cache.
+package tokencache
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+	"golang.org/x/xerrors"
+)
+
+// Locker acquires an advisory, per-issuer+client-id lock around a cache
+// read-modify-write so that only one process authenticates at a time.
+type Locker interface {
+	// Lock blocks until the lock is acquired or ctx is done, and returns
+	// an Unlock function to release it. If timeout elapses before the
+	// lock is acquired, it returns an error.
+	Lock(ctx context.Context, timeout time.Duration) (unlock func(), err error)
+}
+
+// FileLocker implements Locker with an OS-level advisory lock (flock on
+// Unix, LockFileEx on Windows) on a file under dir, named after the
+// issuer URL and client ID. Advisory locks are released automatically by
+// the OS if the holding process crashes, so FileLocker never needs to
+// detect or clean up stale locks itself.
+type FileLocker struct {
+	Dir       string
+	IssuerURL string
+	ClientID  string
+}
+
+// Lock acquires the lockfile, creating dir if necessary.
+func (l *FileLocker) Lock(ctx context.Context, timeout time.Duration) (func(), error) {
+	if err := os.MkdirAll(l.Dir, 0700); err != nil {
+		return nil, xerrors.Errorf("could not create the lock directory: %w", err)
+	}
+	fl := flock.New(filepath.Join(l.Dir, l.filename()))
+
+	lockCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ok, err := fl.TryLockContext(lockCtx, 50*time.Millisecond)
+	if err != nil {
+		return nil, xerrors.Errorf("could not acquire the token cache lock: %w", err)
+	}
+	if !ok {
+		return nil, xerrors.Errorf("timed out waiting for the token cache lock after %s", timeout)
+	}
+	return func() {
+		_ = fl.Unlock()
+	}, nil
+}
+
+func (l *FileLocker) filename() string {
+	return key(l.IssuerURL, l.ClientID) + ".lock"
+}
+
+// key derives the filename stem Repository and FileLocker use for a given
+// issuer+client-id pair, so that a cache file and its lockfile are always
+// co-located under the same name.
+func key(issuerURL, clientID string) string {
+	h := sha256.Sum256([]byte(issuerURL + "/" + clientID))
+	return fmt.Sprintf("%x", h)
+}
+
+// NoopLocker implements Locker as a no-op, for --disable-token-cache-lock.
+type NoopLocker struct{}
+
+// Lock returns immediately with a no-op unlock function.
+func (NoopLocker) Lock(context.Context, time.Duration) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,84 @@
+package tokencache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFileLocker_Lock(t *testing.T) {
+	t.Run("AcquireAndRelease", func(t *testing.T) {
+		dir := t.TempDir()
+		l := &FileLocker{Dir: dir, IssuerURL: "https://issuer.example.com", ClientID: "client-id"}
+		unlock, err := l.Lock(context.TODO(), time.Second)
+		if err != nil {
+			t.Fatalf("Lock() returned an error: %s", err)
+		}
+		unlock()
+	})
+
+	t.Run("DifferentIssuerOrClientDoesNotContend", func(t *testing.T) {
+		dir := t.TempDir()
+		l1 := &FileLocker{Dir: dir, IssuerURL: "https://issuer1.example.com", ClientID: "client-id"}
+		l2 := &FileLocker{Dir: dir, IssuerURL: "https://issuer2.example.com", ClientID: "client-id"}
+
+		unlock1, err := l1.Lock(context.TODO(), time.Second)
+		if err != nil {
+			t.Fatalf("l1.Lock() returned an error: %s", err)
+		}
+		defer unlock1()
+
+		unlock2, err := l2.Lock(context.TODO(), time.Second)
+		if err != nil {
+			t.Fatalf("l2.Lock() returned an error: %s", err)
+		}
+		unlock2()
+	})
+
+	t.Run("ContentionTimesOut", func(t *testing.T) {
+		dir := t.TempDir()
+		l1 := &FileLocker{Dir: dir, IssuerURL: "https://issuer.example.com", ClientID: "client-id"}
+		l2 := &FileLocker{Dir: dir, IssuerURL: "https://issuer.example.com", ClientID: "client-id"}
+
+		unlock1, err := l1.Lock(context.TODO(), time.Second)
+		if err != nil {
+			t.Fatalf("l1.Lock() returned an error: %s", err)
+		}
+		defer unlock1()
+
+		_, err = l2.Lock(context.TODO(), 100*time.Millisecond)
+		if err == nil {
+			t.Fatalf("expected an error but got none")
+		}
+	})
+
+	t.Run("ContentionReleasedInTime", func(t *testing.T) {
+		dir := t.TempDir()
+		l1 := &FileLocker{Dir: dir, IssuerURL: "https://issuer.example.com", ClientID: "client-id"}
+		l2 := &FileLocker{Dir: dir, IssuerURL: "https://issuer.example.com", ClientID: "client-id"}
+
+		unlock1, err := l1.Lock(context.TODO(), time.Second)
+		if err != nil {
+			t.Fatalf("l1.Lock() returned an error: %s", err)
+		}
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			unlock1()
+		}()
+
+		unlock2, err := l2.Lock(context.TODO(), time.Second)
+		if err != nil {
+			t.Fatalf("l2.Lock() returned an error: %s", err)
+		}
+		unlock2()
+	})
+}
+
+func TestNoopLocker_Lock(t *testing.T) {
+	var l NoopLocker
+	unlock, err := l.Lock(context.TODO(), time.Second)
+	if err != nil {
+		t.Fatalf("Lock() returned an error: %s", err)
+	}
+	unlock()
+}
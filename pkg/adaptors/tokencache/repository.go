@@ -0,0 +1,66 @@
+package tokencache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// Entry represents a cached token set for a single issuer+client-id pair.
+type Entry struct {
+	IDToken      string    `json:"id_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// Expired reports whether e is no longer usable as of now.
+func (e Entry) Expired(now time.Time) bool {
+	return !now.Before(e.Expiry)
+}
+
+// Repository reads and writes the token cache file for a single
+// issuer+client-id pair, named after the same key FileLocker uses for its
+// lockfile, so that the two stay co-located under Dir.
+type Repository struct {
+	Dir       string
+	IssuerURL string
+	ClientID  string
+}
+
+// Read returns the cached Entry, or nil if no cache file exists yet.
+func (r *Repository) Read() (*Entry, error) {
+	b, err := os.ReadFile(filepath.Join(r.Dir, r.filename()))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("could not read the token cache: %w", err)
+	}
+	var e Entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, xerrors.Errorf("could not parse the token cache: %w", err)
+	}
+	return &e, nil
+}
+
+// Write stores e as the cached Entry, creating Dir if necessary.
+func (r *Repository) Write(e Entry) error {
+	if err := os.MkdirAll(r.Dir, 0700); err != nil {
+		return xerrors.Errorf("could not create the token cache directory: %w", err)
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return xerrors.Errorf("could not marshal the token cache: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(r.Dir, r.filename()), b, 0600); err != nil {
+		return xerrors.Errorf("could not write the token cache: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) filename() string {
+	return key(r.IssuerURL, r.ClientID) + ".json"
+}
@@ -0,0 +1,196 @@
+// Package kubeconfig models the subset of a kubeconfig file that kubelogin
+// reads and writes: enough of the cluster/context/user/exec shape to locate
+// an existing exec-plugin user or generate a new one, without depending on
+// the full client-go config loader.
+package kubeconfig
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/xerrors"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/util/homedir"
+)
+
+// ContextName is the name of a context in a kubeconfig.
+type ContextName string
+
+// UserName is the name of a user in a kubeconfig.
+type UserName string
+
+// DefaultPathFromEnv returns the conventional kubeconfig path: the value of
+// KUBECONFIG if set, taken as a single path (a list of several paths is not
+// split), or else ~/.kube/config.
+func DefaultPathFromEnv() string {
+	if v := os.Getenv("KUBECONFIG"); v != "" {
+		return v
+	}
+	return homedir.HomeDir() + "/.kube/config"
+}
+
+// Cluster represents a cluster entry.
+type Cluster struct {
+	Server                   string `yaml:"server"`
+	CertificateAuthority     string `yaml:"certificate-authority,omitempty"`
+	CertificateAuthorityData string `yaml:"certificate-authority-data,omitempty"`
+	InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify,omitempty"`
+}
+
+// NamedCluster associates a name with a Cluster.
+type NamedCluster struct {
+	Name    string  `yaml:"name"`
+	Cluster Cluster `yaml:"cluster"`
+}
+
+// Context represents a context entry.
+type Context struct {
+	Cluster string `yaml:"cluster"`
+	User    string `yaml:"user"`
+}
+
+// NamedContext associates a name with a Context.
+type NamedContext struct {
+	Name    string  `yaml:"name"`
+	Context Context `yaml:"context"`
+}
+
+// ExecEnvVar is a single environment variable passed to an exec plugin.
+type ExecEnvVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// ExecConfig represents a users[].user.exec block, matching the
+// client.authentication.k8s.io ExecCredential plugin configuration.
+type ExecConfig struct {
+	APIVersion      string       `yaml:"apiVersion"`
+	Command         string       `yaml:"command"`
+	Args            []string     `yaml:"args,omitempty"`
+	Env             []ExecEnvVar `yaml:"env,omitempty"`
+	InstallHint     string       `yaml:"installHint,omitempty"`
+	InteractiveMode string       `yaml:"interactiveMode,omitempty"`
+}
+
+// User represents a users[].user entry.
+type User struct {
+	Exec *ExecConfig `yaml:"exec,omitempty"`
+}
+
+// NamedUser associates a name with a User.
+type NamedUser struct {
+	Name string `yaml:"name"`
+	User User   `yaml:"user"`
+}
+
+// Config models a kubeconfig file.
+type Config struct {
+	APIVersion     string         `yaml:"apiVersion"`
+	Kind           string         `yaml:"kind"`
+	Clusters       []NamedCluster `yaml:"clusters"`
+	Contexts       []NamedContext `yaml:"contexts"`
+	Users          []NamedUser    `yaml:"users"`
+	CurrentContext string         `yaml:"current-context,omitempty"`
+}
+
+// Read parses the kubeconfig at filename. It returns an empty Config,
+// rather than an error, if filename does not exist yet, so that callers
+// can patch a kubeconfig that has not been created yet.
+func Read(filename string) (*Config, error) {
+	b, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return &Config{APIVersion: "v1", Kind: "Config"}, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("could not read %s: %w", filename, err)
+	}
+	var c Config
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, xerrors.Errorf("could not parse %s as YAML: %w", filename, err)
+	}
+	return &c, nil
+}
+
+// Write serializes c as YAML to filename, creating parent directories as
+// needed.
+func Write(filename string, c *Config) error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return xerrors.Errorf("could not marshal the kubeconfig: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return xerrors.Errorf("could not create the directory of %s: %w", filename, err)
+	}
+	if err := os.WriteFile(filename, b, 0600); err != nil {
+		return xerrors.Errorf("could not write %s: %w", filename, err)
+	}
+	return nil
+}
+
+// Encode serializes c as YAML to w, e.g. to print it to stdout without
+// writing a file.
+func Encode(w io.Writer, c *Config) error {
+	if err := yaml.NewEncoder(w).Encode(c); err != nil {
+		return xerrors.Errorf("could not marshal the kubeconfig: %w", err)
+	}
+	return nil
+}
+
+// FindContext returns the context named name, or the current context if
+// name is empty.
+func (c *Config) FindContext(name ContextName) *Context {
+	target := string(name)
+	if target == "" {
+		target = c.CurrentContext
+	}
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == target {
+			return &c.Contexts[i].Context
+		}
+	}
+	return nil
+}
+
+// FindUser returns the user named name, or nil if not found.
+func (c *Config) FindUser(name UserName) *User {
+	for i := range c.Users {
+		if c.Users[i].Name == string(name) {
+			return &c.Users[i].User
+		}
+	}
+	return nil
+}
+
+// SetUser inserts or replaces the user named name.
+func (c *Config) SetUser(name UserName, u User) {
+	for i := range c.Users {
+		if c.Users[i].Name == string(name) {
+			c.Users[i].User = u
+			return
+		}
+	}
+	c.Users = append(c.Users, NamedUser{Name: string(name), User: u})
+}
+
+// SetCluster inserts or replaces the cluster named name.
+func (c *Config) SetCluster(name string, cluster Cluster) {
+	for i := range c.Clusters {
+		if c.Clusters[i].Name == name {
+			c.Clusters[i].Cluster = cluster
+			return
+		}
+	}
+	c.Clusters = append(c.Clusters, NamedCluster{Name: name, Cluster: cluster})
+}
+
+// SetContext inserts or replaces the context named name.
+func (c *Config) SetContext(name string, context Context) {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			c.Contexts[i].Context = context
+			return
+		}
+	}
+	c.Contexts = append(c.Contexts, NamedContext{Name: name, Context: context})
+}
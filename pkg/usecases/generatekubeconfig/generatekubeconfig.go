@@ -0,0 +1,89 @@
+// Package generatekubeconfig implements the GenerateKubeconfig use-case.
+package generatekubeconfig
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/google/wire"
+	"github.com/int128/kubelogin/pkg/models/kubeconfig"
+	"github.com/int128/kubelogin/pkg/usecases"
+	"golang.org/x/xerrors"
+)
+
+// Set provides an implementation and interface for GenerateKubeconfig.
+var Set = wire.NewSet(
+	wire.Struct(new(GenerateKubeconfig), "*"),
+	wire.Bind(new(usecases.GenerateKubeconfig), new(*GenerateKubeconfig)),
+)
+
+// execAPIVersion is the ExecCredential API version kubelogin implements.
+const execAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+// GenerateKubeconfig implements usecases.GenerateKubeconfig.
+type GenerateKubeconfig struct{}
+
+// Do writes (or patches an existing) kubeconfig at in.KubeconfigFilename,
+// or in.Output if set, with a users[].exec block that invokes
+// "in.Executable get-token" with the given OIDC settings. If
+// in.KubeconfigFilename is empty, it builds a standalone kubeconfig, which
+// is printed to stdout unless in.Output is set.
+func (u *GenerateKubeconfig) Do(_ context.Context, in usecases.GenerateKubeconfigIn) error {
+	contextName := in.Context
+	if contextName == "" {
+		contextName = in.Cluster
+	}
+	userName := in.User
+	if userName == "" {
+		userName = in.Cluster
+	}
+
+	c := &kubeconfig.Config{APIVersion: "v1", Kind: "Config"}
+	if in.KubeconfigFilename != "" {
+		var err error
+		c, err = kubeconfig.Read(in.KubeconfigFilename)
+		if err != nil {
+			return xerrors.Errorf("could not read the kubeconfig: %w", err)
+		}
+	}
+
+	args := []string{"get-token",
+		"--oidc-issuer-url=" + in.IssuerURL,
+		"--oidc-client-id=" + in.ClientID,
+	}
+	if in.ClientSecret != "" {
+		args = append(args, "--oidc-client-secret="+in.ClientSecret)
+	}
+	for _, scope := range in.ExtraScopes {
+		args = append(args, "--oidc-extra-scope="+scope)
+	}
+
+	// Only scaffold a cluster entry when patching an existing kubeconfig
+	// did not already have one; this command has no --server flag of its
+	// own, so it must not clobber the server/CA data of a cluster entry
+	// the user (or `kubectl config set-cluster`) already populated.
+	if len(c.Clusters) == 0 {
+		c.SetCluster(in.Cluster, kubeconfig.Cluster{})
+	}
+	c.SetContext(contextName, kubeconfig.Context{Cluster: in.Cluster, User: userName})
+	c.SetUser(kubeconfig.UserName(userName), kubeconfig.User{
+		Exec: &kubeconfig.ExecConfig{
+			APIVersion:      execAPIVersion,
+			Command:         in.Executable,
+			Args:            args,
+			InstallHint:     fmt.Sprintf("kubelogin is required to authenticate to this cluster. Install it from https://github.com/int128/kubelogin and make %s available on PATH.", in.Executable),
+			InteractiveMode: "IfAvailable",
+		},
+	})
+	c.CurrentContext = contextName
+
+	switch {
+	case in.Output != "":
+		return kubeconfig.Write(in.Output, c)
+	case in.KubeconfigFilename != "":
+		return kubeconfig.Write(in.KubeconfigFilename, c)
+	default:
+		return kubeconfig.Encode(os.Stdout, c)
+	}
+}
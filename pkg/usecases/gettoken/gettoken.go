@@ -0,0 +1,268 @@
+// Package gettoken implements the GetToken use-case: the kubectl
+// credential plugin entrypoint.
+package gettoken
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/int128/kubelogin/pkg/adaptors/authn"
+	"github.com/int128/kubelogin/pkg/adaptors/authn/ldap"
+	"github.com/int128/kubelogin/pkg/adaptors/authn/staticpasswords"
+	"github.com/int128/kubelogin/pkg/adaptors/listener"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient"
+	"github.com/int128/kubelogin/pkg/adaptors/tlsconfig"
+	"github.com/int128/kubelogin/pkg/adaptors/tokencache"
+	"github.com/int128/kubelogin/pkg/usecases"
+	"golang.org/x/xerrors"
+)
+
+// Set provides an implementation and interface for GetToken.
+var Set = wire.NewSet(
+	wire.Struct(new(GetToken), "*"),
+	wire.Bind(new(usecases.GetToken), new(*GetToken)),
+)
+
+// defaultTokenTTL is used as the cached Entry's expiry when the token
+// response does not let kubelogin determine the ID token's own exp claim
+// without pulling in a full JWT parser.
+const defaultTokenTTL = 1 * time.Hour
+
+// execCredential is the subset of the client.authentication.k8s.io
+// ExecCredential response that kubelogin emits on stdout.
+type execCredential struct {
+	APIVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Status     execCredentialStatus `json:"status"`
+}
+
+type execCredentialStatus struct {
+	Token               string `json:"token"`
+	ExpirationTimestamp string `json:"expirationTimestamp"`
+}
+
+// GetToken implements usecases.GetToken.
+type GetToken struct{}
+
+// Do returns a cached, still-valid ID token if one is available, or
+// authenticates and caches a new one, then writes an ExecCredential to
+// stdout.
+func (u *GetToken) Do(ctx context.Context, in usecases.GetTokenIn) error {
+	var locker tokencache.Locker = &tokencache.FileLocker{Dir: in.TokenCacheDir, IssuerURL: in.IssuerURL, ClientID: in.ClientID}
+	if in.DisableTokenCacheLock {
+		locker = tokencache.NoopLocker{}
+	}
+	unlock, err := locker.Lock(ctx, in.TokenCacheLockTimeout)
+	if err != nil {
+		return xerrors.Errorf("could not acquire the token cache lock: %w", err)
+	}
+	defer unlock()
+
+	repo := tokencache.Repository{Dir: in.TokenCacheDir, IssuerURL: in.IssuerURL, ClientID: in.ClientID}
+	now := time.Now()
+	if cached, err := repo.Read(); err != nil {
+		return xerrors.Errorf("could not read the token cache: %w", err)
+	} else if cached != nil && !cached.Expired(now) {
+		return writeExecCredential(os.Stdout, *cached)
+	}
+
+	entry, err := u.authenticate(ctx, in)
+	if err != nil {
+		return xerrors.Errorf("could not authenticate: %w", err)
+	}
+	if err := repo.Write(*entry); err != nil {
+		return xerrors.Errorf("could not write the token cache: %w", err)
+	}
+	return writeExecCredential(os.Stdout, *entry)
+}
+
+func (u *GetToken) authenticate(ctx context.Context, in usecases.GetTokenIn) (*tokencache.Entry, error) {
+	tlsConfig, err := tlsconfig.New(tlsconfig.Options{
+		Profile:      in.OIDCTLS.Profile,
+		MinVersion:   in.OIDCTLS.MinVersion,
+		CipherSuites: in.OIDCTLS.CipherSuites,
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("could not build the OIDC TLS config: %w", err)
+	}
+	tlsConfig.InsecureSkipVerify = in.SkipTLSVerify
+	if in.CACertFilename != "" {
+		b, err := os.ReadFile(in.CACertFilename)
+		if err != nil {
+			return nil, xerrors.Errorf("could not read the certificate authority: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, xerrors.Errorf("could not parse %s as PEM certificates", in.CACertFilename)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	oidcClient := oidcclient.New(in.IssuerURL, tlsConfig)
+
+	if in.Username != "" {
+		connector, err := resolveConnector(in.AuthConnector, in.LDAP, in.StaticPasswordsFile)
+		if err != nil {
+			return nil, xerrors.Errorf("could not resolve the auth connector: %w", err)
+		}
+		if connector != nil {
+			identity, err := connector.Authenticate(ctx, in.Username, in.Password)
+			if err != nil {
+				return nil, xerrors.Errorf("could not authenticate via the %s connector: %w", in.AuthConnector, err)
+			}
+			idToken, expiry, err := authn.Synthesize(identity, in.IssuerURL, in.ClientID, defaultTokenTTL)
+			if err != nil {
+				return nil, xerrors.Errorf("could not synthesize the ID token: %w", err)
+			}
+			return &tokencache.Entry{IDToken: idToken, Expiry: expiry}, nil
+		}
+		idToken, err := oidcClient.ExchangeROPC(ctx, in.ClientID, in.ClientSecret, in.Username, in.Password)
+		if err != nil {
+			return nil, xerrors.Errorf("could not exchange the password for a token: %w", err)
+		}
+		return &tokencache.Entry{IDToken: idToken, Expiry: time.Now().Add(defaultTokenTTL)}, nil
+	}
+
+	idToken, err := authenticateByBrowser(ctx, in.ListenAddrs, in.RedirectURLHostname, in.SkipOpenBrowser, oidcClient, in.ClientID, in.ClientSecret, in.ExtraScopes)
+	if err != nil {
+		return nil, xerrors.Errorf("could not authenticate via the browser: %w", err)
+	}
+	return &tokencache.Entry{IDToken: idToken, Expiry: time.Now().Add(defaultTokenTTL)}, nil
+}
+
+// resolveConnector returns the authn.Connector for connectorName, or nil
+// when connectorName selects the OIDC issuer's own ROPC grant directly
+// (no local bind is involved, so there is nothing to synthesize an ID
+// token from).
+func resolveConnector(connectorName string, o usecases.LDAPOptions, staticPasswordsFile string) (authn.Connector, error) {
+	switch connectorName {
+	case "", "oidc-ropc", "authcode":
+		return nil, nil
+	case "ldap":
+		return ldap.New(ldap.Config{
+			Host:               o.Host,
+			BindDN:             o.BindDN,
+			BindPassword:       o.BindPassword,
+			UserSearchBaseDN:   o.UserSearchBaseDN,
+			UserSearchFilter:   o.UserSearchFilter,
+			GroupSearchBaseDN:  o.GroupSearchBaseDN,
+			GroupSearchFilter:  o.GroupSearchFilter,
+			GroupAttribute:     o.GroupAttribute,
+			InsecureSkipVerify: o.InsecureSkipVerify,
+		}), nil
+	case "static-passwords":
+		return staticpasswords.New(staticPasswordsFile), nil
+	default:
+		return nil, xerrors.Errorf("unknown auth connector %q", connectorName)
+	}
+}
+
+// authenticateByBrowser binds the first listenAddr that succeeds, prints
+// the authorization URL, waits for the redirect carrying the
+// authorization code, and exchanges it for an ID token.
+func authenticateByBrowser(ctx context.Context, listenAddrs []string, redirectURLHostname string, skipOpenBrowser bool, oidcClient *oidcclient.Client, clientID, clientSecret string, extraScopes []string) (string, error) {
+	var lastErr error
+	for _, addr := range listenAddrs {
+		l, err := listener.Listen(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return runCallbackServer(ctx, l, redirectURLHostname, skipOpenBrowser, oidcClient, clientID, clientSecret, extraScopes)
+	}
+	return "", xerrors.Errorf("could not bind any of %v: %w", listenAddrs, lastErr)
+}
+
+// runCallbackServer serves the redirect callback on l, prints the
+// authorization URL for the user to open (kubelogin does not depend on a
+// browser-opening library), and blocks until the callback delivers an
+// authorization code or ctx is done.
+func runCallbackServer(ctx context.Context, l net.Listener, redirectURLHostname string, skipOpenBrowser bool, oidcClient *oidcclient.Client, clientID, clientSecret string, extraScopes []string) (string, error) {
+	defer l.Close()
+
+	state, err := randomState()
+	if err != nil {
+		return "", xerrors.Errorf("could not generate the state parameter: %w", err)
+	}
+	redirectURI := fmt.Sprintf("http://%s/callback", redirectURLHostname)
+	if tcpAddr, ok := l.Addr().(*net.TCPAddr); ok {
+		redirectURI = fmt.Sprintf("http://%s:%d/callback", redirectURLHostname, tcpAddr.Port)
+	}
+
+	authCodeURL, err := oidcClient.AuthCodeURL(ctx, clientID, redirectURI, state, extraScopes)
+	if err != nil {
+		return "", xerrors.Errorf("could not build the authorization URL: %w", err)
+	}
+	if !skipOpenBrowser {
+		fmt.Fprintf(os.Stderr, "Please open the following URL in your browser:\n\n%s\n\n", authCodeURL)
+	} else {
+		fmt.Fprintf(os.Stderr, "Please visit the following URL:\n\n%s\n\n", authCodeURL)
+	}
+
+	codeCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("state") != state {
+			errCh <- xerrors.New("state parameter did not match")
+			http.Error(w, "state parameter did not match", http.StatusBadRequest)
+			return
+		}
+		if errMsg := q.Get("error"); errMsg != "" {
+			errCh <- xerrors.Errorf("authorization server returned an error: %s", errMsg)
+			http.Error(w, errMsg, http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, "Authenticated. You may close this tab and return to the terminal.")
+		codeCh <- q.Get("code")
+	})}
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Close()
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case err := <-errCh:
+		return "", err
+	case code := <-codeCh:
+		idToken, err := oidcClient.ExchangeCode(ctx, clientID, clientSecret, code, redirectURI)
+		if err != nil {
+			return "", xerrors.Errorf("could not exchange the code for a token: %w", err)
+		}
+		return idToken, nil
+	}
+}
+
+// randomState returns a random, URL-safe string suitable for the OAuth2
+// state parameter, which guards against cross-site request forgery on the
+// redirect callback.
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", xerrors.Errorf("could not read random bytes: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// writeExecCredential writes e as a client.authentication.k8s.io
+// ExecCredential response, the format kubectl expects on stdout from a
+// credential plugin.
+func writeExecCredential(w *os.File, e tokencache.Entry) error {
+	cred := execCredential{
+		APIVersion: "client.authentication.k8s.io/v1beta1",
+		Kind:       "ExecCredential",
+		Status: execCredentialStatus{
+			Token:               e.IDToken,
+			ExpirationTimestamp: e.Expiry.Format(time.RFC3339),
+		},
+	}
+	return json.NewEncoder(w).Encode(cred)
+}
@@ -0,0 +1,245 @@
+// Package login implements the Login use-case: reading the OIDC settings
+// of kubectl's credential plugin config from the kubeconfig and performing
+// one authentication to confirm they work. It does not modify the
+// kubeconfig and writes nothing to the token cache.
+package login
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/int128/kubelogin/pkg/adaptors/authn"
+	"github.com/int128/kubelogin/pkg/adaptors/authn/ldap"
+	"github.com/int128/kubelogin/pkg/adaptors/authn/staticpasswords"
+	"github.com/int128/kubelogin/pkg/adaptors/listener"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient"
+	"github.com/int128/kubelogin/pkg/adaptors/tlsconfig"
+	"github.com/int128/kubelogin/pkg/models/kubeconfig"
+	"github.com/int128/kubelogin/pkg/usecases"
+	"golang.org/x/xerrors"
+)
+
+// Set provides an implementation and interface for Login.
+var Set = wire.NewSet(
+	wire.Struct(new(Login), "*"),
+	wire.Bind(new(usecases.Login), new(*Login)),
+)
+
+// Login implements usecases.Login.
+type Login struct{}
+
+// Do locates the OIDC settings of the exec plugin configured for
+// in.KubeconfigContext (or the current context) in in.KubeconfigUser (or
+// that context's user), and performs one authentication against the
+// issuer to confirm the settings work.
+func (u *Login) Do(ctx context.Context, in usecases.LoginIn) error {
+	filename := in.KubeconfigFilename
+	if filename == "" {
+		filename = kubeconfig.DefaultPathFromEnv()
+	}
+	c, err := kubeconfig.Read(filename)
+	if err != nil {
+		return xerrors.Errorf("could not read the kubeconfig: %w", err)
+	}
+
+	kubeconfigContext := c.FindContext(in.KubeconfigContext)
+	if kubeconfigContext == nil {
+		return xerrors.Errorf("context %q was not found in %s", in.KubeconfigContext, filename)
+	}
+	userName := in.KubeconfigUser
+	if userName == "" {
+		userName = kubeconfig.UserName(kubeconfigContext.User)
+	}
+	user := c.FindUser(userName)
+	if user == nil || user.Exec == nil {
+		return xerrors.Errorf("user %q in %s has no exec plugin configured", userName, filename)
+	}
+
+	issuerURL, clientID, clientSecret, extraScopes, err := parseExecArgs(user.Exec.Args)
+	if err != nil {
+		return xerrors.Errorf("could not parse the exec plugin args of user %q: %w", userName, err)
+	}
+
+	tlsConfig, err := tlsconfig.New(tlsconfig.Options{
+		Profile:      in.OIDCTLS.Profile,
+		MinVersion:   in.OIDCTLS.MinVersion,
+		CipherSuites: in.OIDCTLS.CipherSuites,
+	})
+	if err != nil {
+		return xerrors.Errorf("could not build the OIDC TLS config: %w", err)
+	}
+	oidcClient := oidcclient.New(issuerURL, tlsConfig)
+
+	if in.Username != "" {
+		connector, err := resolveConnector(in.AuthConnector, in.LDAP, in.StaticPasswordsFile)
+		if err != nil {
+			return xerrors.Errorf("could not resolve the auth connector: %w", err)
+		}
+		if connector != nil {
+			identity, err := connector.Authenticate(ctx, in.Username, in.Password)
+			if err != nil {
+				return xerrors.Errorf("could not authenticate via the %s connector: %w", in.AuthConnector, err)
+			}
+			if _, _, err := authn.Synthesize(identity, issuerURL, clientID, time.Hour); err != nil {
+				return xerrors.Errorf("could not synthesize the ID token: %w", err)
+			}
+			return nil
+		}
+		if _, err := oidcClient.ExchangeROPC(ctx, clientID, clientSecret, in.Username, in.Password); err != nil {
+			return xerrors.Errorf("could not exchange the password for a token: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := authenticateByBrowser(ctx, in.ListenAddrs, in.RedirectURLHostname, in.SkipOpenBrowser, oidcClient, clientID, clientSecret, extraScopes); err != nil {
+		return xerrors.Errorf("could not authenticate via the browser: %w", err)
+	}
+	return nil
+}
+
+// parseExecArgs recovers the OIDC issuer, client ID, client secret and
+// extra scopes from the "get-token" args generated by
+// usecases.GenerateKubeconfig, so that Login does not need its own copy
+// of those settings.
+func parseExecArgs(args []string) (issuerURL, clientID, clientSecret string, extraScopes []string, err error) {
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--oidc-issuer-url="):
+			issuerURL = strings.TrimPrefix(arg, "--oidc-issuer-url=")
+		case strings.HasPrefix(arg, "--oidc-client-id="):
+			clientID = strings.TrimPrefix(arg, "--oidc-client-id=")
+		case strings.HasPrefix(arg, "--oidc-client-secret="):
+			clientSecret = strings.TrimPrefix(arg, "--oidc-client-secret=")
+		case strings.HasPrefix(arg, "--oidc-extra-scope="):
+			extraScopes = append(extraScopes, strings.TrimPrefix(arg, "--oidc-extra-scope="))
+		}
+	}
+	if issuerURL == "" || clientID == "" {
+		return "", "", "", nil, xerrors.New("exec plugin args do not contain --oidc-issuer-url and --oidc-client-id")
+	}
+	return issuerURL, clientID, clientSecret, extraScopes, nil
+}
+
+// resolveConnector returns the authn.Connector for connectorName, or nil
+// when connectorName selects the OIDC issuer's own ROPC grant directly
+// (no local bind is involved, so there is nothing to synthesize an ID
+// token from).
+func resolveConnector(connectorName string, o usecases.LDAPOptions, staticPasswordsFile string) (authn.Connector, error) {
+	switch connectorName {
+	case "", "oidc-ropc", "authcode":
+		return nil, nil
+	case "ldap":
+		return ldap.New(ldap.Config{
+			Host:               o.Host,
+			BindDN:             o.BindDN,
+			BindPassword:       o.BindPassword,
+			UserSearchBaseDN:   o.UserSearchBaseDN,
+			UserSearchFilter:   o.UserSearchFilter,
+			GroupSearchBaseDN:  o.GroupSearchBaseDN,
+			GroupSearchFilter:  o.GroupSearchFilter,
+			GroupAttribute:     o.GroupAttribute,
+			InsecureSkipVerify: o.InsecureSkipVerify,
+		}), nil
+	case "static-passwords":
+		return staticpasswords.New(staticPasswordsFile), nil
+	default:
+		return nil, xerrors.Errorf("unknown auth connector %q", connectorName)
+	}
+}
+
+// authenticateByBrowser binds the first listenAddr that succeeds, prints
+// the authorization URL, waits for the redirect carrying the
+// authorization code, and exchanges it for an ID token. This mirrors
+// usecases/gettoken's authenticateByBrowser; the two are kept as separate
+// copies since Login discards the resulting token while GetToken caches
+// it, and a shared package would only save this one function.
+func authenticateByBrowser(ctx context.Context, listenAddrs []string, redirectURLHostname string, skipOpenBrowser bool, oidcClient *oidcclient.Client, clientID, clientSecret string, extraScopes []string) (string, error) {
+	var lastErr error
+	for _, addr := range listenAddrs {
+		l, err := listener.Listen(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return runCallbackServer(ctx, l, redirectURLHostname, skipOpenBrowser, oidcClient, clientID, clientSecret, extraScopes)
+	}
+	return "", xerrors.Errorf("could not bind any of %v: %w", listenAddrs, lastErr)
+}
+
+// runCallbackServer serves the redirect callback on l, prints the
+// authorization URL for the user to open, and blocks until the callback
+// delivers an authorization code or ctx is done.
+func runCallbackServer(ctx context.Context, l net.Listener, redirectURLHostname string, skipOpenBrowser bool, oidcClient *oidcclient.Client, clientID, clientSecret string, extraScopes []string) (string, error) {
+	defer l.Close()
+
+	state, err := randomState()
+	if err != nil {
+		return "", xerrors.Errorf("could not generate the state parameter: %w", err)
+	}
+	redirectURI := fmt.Sprintf("http://%s/callback", redirectURLHostname)
+	if tcpAddr, ok := l.Addr().(*net.TCPAddr); ok {
+		redirectURI = fmt.Sprintf("http://%s:%d/callback", redirectURLHostname, tcpAddr.Port)
+	}
+
+	authCodeURL, err := oidcClient.AuthCodeURL(ctx, clientID, redirectURI, state, extraScopes)
+	if err != nil {
+		return "", xerrors.Errorf("could not build the authorization URL: %w", err)
+	}
+	if !skipOpenBrowser {
+		fmt.Fprintf(os.Stderr, "Please open the following URL in your browser:\n\n%s\n\n", authCodeURL)
+	} else {
+		fmt.Fprintf(os.Stderr, "Please visit the following URL:\n\n%s\n\n", authCodeURL)
+	}
+
+	codeCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("state") != state {
+			errCh <- xerrors.New("state parameter did not match")
+			http.Error(w, "state parameter did not match", http.StatusBadRequest)
+			return
+		}
+		if errMsg := q.Get("error"); errMsg != "" {
+			errCh <- xerrors.Errorf("authorization server returned an error: %s", errMsg)
+			http.Error(w, errMsg, http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, "Authenticated. You may close this tab and return to the terminal.")
+		codeCh <- q.Get("code")
+	})}
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Close()
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case err := <-errCh:
+		return "", err
+	case code := <-codeCh:
+		idToken, err := oidcClient.ExchangeCode(ctx, clientID, clientSecret, code, redirectURI)
+		if err != nil {
+			return "", xerrors.Errorf("could not exchange the code for a token: %w", err)
+		}
+		return idToken, nil
+	}
+}
+
+// randomState returns a random, URL-safe string suitable for the OAuth2
+// state parameter, which guards against cross-site request forgery on the
+// redirect callback.
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", xerrors.Errorf("could not read random bytes: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
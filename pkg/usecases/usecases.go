@@ -0,0 +1,114 @@
+// Package usecases declares the application's use cases as interfaces, so
+// that pkg/adaptors/cmd can depend on behavior without depending on how
+// that behavior is implemented. Each interface has a concrete
+// implementation in a sibling package (pkg/usecases/login,
+// pkg/usecases/gettoken, pkg/usecases/generatekubeconfig) bound to it via
+// wire.
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/int128/kubelogin/pkg/adaptors/tlsconfig"
+	"github.com/int128/kubelogin/pkg/models/kubeconfig"
+)
+
+// TLSOptions selects and overrides a TLS profile for a single outbound
+// HTTPS client; see pkg/adaptors/tlsconfig.
+type TLSOptions struct {
+	Profile      tlsconfig.Profile
+	MinVersion   string
+	CipherSuites []string
+}
+
+// LDAPOptions configures the LDAP authn connector; see
+// pkg/adaptors/authn/ldap.
+type LDAPOptions struct {
+	Host               string
+	BindDN             string
+	BindPassword       string
+	UserSearchBaseDN   string
+	UserSearchFilter   string
+	GroupSearchBaseDN  string
+	GroupSearchFilter  string
+	GroupAttribute     string
+	InsecureSkipVerify bool
+}
+
+// LoginIn represents the input DTO of the Login use-case.
+type LoginIn struct {
+	KubeconfigFilename  string
+	KubeconfigContext   kubeconfig.ContextName
+	KubeconfigUser      kubeconfig.UserName
+	CACertFilename      string
+	SkipTLSVerify       bool
+	APIServerTLS        TLSOptions
+	OIDCTLS             TLSOptions
+	ListenAddrs         []string
+	RedirectURLHostname string
+	SkipOpenBrowser     bool
+	Username            string
+	Password            string
+	AuthConnector       string
+	LDAP                LDAPOptions
+	StaticPasswordsFile string
+}
+
+// Login performs a one-off login to verify the credentials configured for
+// a kubeconfig context work. It does not write the token cache that the
+// matching get-token invocation reads.
+type Login interface {
+	Do(ctx context.Context, in LoginIn) error
+}
+
+// GetTokenIn represents the input DTO of the GetToken use-case.
+type GetTokenIn struct {
+	IssuerURL             string
+	ClientID              string
+	ClientSecret          string
+	ExtraScopes           []string
+	CACertFilename        string
+	SkipTLSVerify         bool
+	OIDCTLS               TLSOptions
+	ListenAddrs           []string
+	RedirectURLHostname   string
+	SkipOpenBrowser       bool
+	Username              string
+	Password              string
+	AuthConnector         string
+	LDAP                  LDAPOptions
+	StaticPasswordsFile   string
+	TokenCacheDir         string
+	TokenCacheLockTimeout time.Duration
+	DisableTokenCacheLock bool
+}
+
+// GetToken runs as a kubectl credential plugin: it returns a cached ID
+// token if one is still valid, or obtains a new one and writes it to the
+// ExecCredential JSON format on stdout.
+type GetToken interface {
+	Do(ctx context.Context, in GetTokenIn) error
+}
+
+// GenerateKubeconfigIn represents the input DTO of the GenerateKubeconfig
+// use-case.
+type GenerateKubeconfigIn struct {
+	Executable         string
+	KubeconfigFilename string
+	Cluster            string
+	Context            string
+	User               string
+	Output             string
+	IssuerURL          string
+	ClientID           string
+	ClientSecret       string
+	ExtraScopes        []string
+}
+
+// GenerateKubeconfig writes (or patches an existing) kubeconfig with a
+// users[].exec block that invokes Executable get-token with the given
+// OIDC settings.
+type GenerateKubeconfig interface {
+	Do(ctx context.Context, in GenerateKubeconfigIn) error
+}